@@ -0,0 +1,123 @@
+// Package acl enforces attribute-based access control on top of a caller's
+// Fabric MSP identity, as surfaced by the chaincode-go cid library. It keeps
+// the role/identity checks in one place so contract methods stay focused on
+// business logic.
+package acl
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// RoleAttribute is the certificate attribute carrying a caller's role.
+const RoleAttribute = "role"
+
+// EnrollmentIDAttribute is the Fabric CA attribute carrying a caller's
+// enrollment ID.
+const EnrollmentIDAttribute = "hf.EnrollmentID"
+
+// Known roles.
+const (
+	RoleLoanOfficer = "loan_officer"
+	RoleApplicant   = "applicant"
+	RoleAdmin       = "admin"
+)
+
+// BankMSPID is the MSP expected to issue loan officer identities.
+const BankMSPID = "BankMSP"
+
+// Identity describes the caller invoking the current transaction.
+type Identity struct {
+	MSPID        string
+	EnrollmentID string
+	CommonName   string
+	Role         string
+}
+
+// CallerIdentity extracts the MSP ID, enrollment ID, CN, and role attribute
+// of the identity that submitted the current transaction.
+func CallerIdentity(ctx contractapi.TransactionContextInterface) (Identity, error) {
+	clientIdentity := ctx.GetClientIdentity()
+
+	mspID, err := clientIdentity.GetMSPID()
+	if err != nil {
+		return Identity{}, fmt.Errorf("failed to get caller MSP ID: %v", err)
+	}
+
+	cert, err := clientIdentity.GetX509Certificate()
+	if err != nil {
+		return Identity{}, fmt.Errorf("failed to get caller certificate: %v", err)
+	}
+
+	enrollmentID, found, err := clientIdentity.GetAttributeValue(EnrollmentIDAttribute)
+	if err != nil {
+		return Identity{}, fmt.Errorf("failed to get caller enrollment ID: %v", err)
+	}
+	if !found {
+		enrollmentID = cert.Subject.CommonName
+	}
+
+	role, _, err := clientIdentity.GetAttributeValue(RoleAttribute)
+	if err != nil {
+		return Identity{}, fmt.Errorf("failed to get caller role: %v", err)
+	}
+
+	return Identity{
+		MSPID:        mspID,
+		EnrollmentID: enrollmentID,
+		CommonName:   cert.Subject.CommonName,
+		Role:         role,
+	}, nil
+}
+
+// RequireRole verifies that the caller belongs to mspID and carries the
+// given role attribute, returning the resolved Identity on success.
+func RequireRole(ctx contractapi.TransactionContextInterface, mspID, role string) (Identity, error) {
+	identity, err := CallerIdentity(ctx)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	if identity.MSPID != mspID {
+		return Identity{}, fmt.Errorf("caller MSP %s is not authorized, expected %s", identity.MSPID, mspID)
+	}
+	if identity.Role != role {
+		return Identity{}, fmt.Errorf("caller does not have required role %s", role)
+	}
+
+	return identity, nil
+}
+
+// RequireAnyMSPRole verifies that the caller carries the given role
+// attribute, regardless of which MSP they belong to, returning the
+// resolved Identity on success. Use this for roles (like applicant) that
+// are not restricted to a single organization.
+func RequireAnyMSPRole(ctx contractapi.TransactionContextInterface, role string) (Identity, error) {
+	identity, err := CallerIdentity(ctx)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	if identity.Role != role {
+		return Identity{}, fmt.Errorf("caller does not have required role %s", role)
+	}
+
+	return identity, nil
+}
+
+// RequireOwner verifies that the caller's MSP ID and enrollment ID match the
+// applicant recorded against a loan, i.e. that the caller is the original
+// applicant.
+func RequireOwner(ctx contractapi.TransactionContextInterface, applicantMSPID, applicantEnrollmentID string) (Identity, error) {
+	identity, err := CallerIdentity(ctx)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	if identity.MSPID != applicantMSPID || identity.EnrollmentID != applicantEnrollmentID {
+		return Identity{}, fmt.Errorf("caller is not the original applicant for this loan")
+	}
+
+	return identity, nil
+}
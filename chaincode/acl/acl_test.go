@@ -0,0 +1,64 @@
+package acl_test
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+
+	"loan-chaincode/acl"
+	"loan-chaincode/mocks"
+)
+
+func newCtx(mspID, role, enrollmentID, commonName string) *mocks.TransactionContext {
+	return &mocks.TransactionContext{
+		Stub: mocks.NewChaincodeStub(),
+		ClientIdentity: &mocks.ClientIdentity{
+			MSPID: mspID,
+			Cert:  &x509.Certificate{Subject: pkix.Name{CommonName: commonName}},
+			Attributes: map[string]string{
+				acl.RoleAttribute:         role,
+				acl.EnrollmentIDAttribute: enrollmentID,
+			},
+		},
+	}
+}
+
+func TestRequireRole_Success(t *testing.T) {
+	ctx := newCtx(acl.BankMSPID, acl.RoleLoanOfficer, "officer1", "Officer One")
+
+	identity, err := acl.RequireRole(ctx, acl.BankMSPID, acl.RoleLoanOfficer)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if identity.MSPID != acl.BankMSPID {
+		t.Errorf("expected MSPID %s, got %s", acl.BankMSPID, identity.MSPID)
+	}
+}
+
+func TestRequireRole_WrongMSP(t *testing.T) {
+	ctx := newCtx("Org1MSP", acl.RoleLoanOfficer, "officer1", "Officer One")
+
+	if _, err := acl.RequireRole(ctx, acl.BankMSPID, acl.RoleLoanOfficer); err == nil {
+		t.Fatal("expected error for caller outside BankMSP, got nil")
+	}
+}
+
+func TestRequireRole_WrongRole(t *testing.T) {
+	ctx := newCtx(acl.BankMSPID, acl.RoleApplicant, "officer1", "Officer One")
+
+	if _, err := acl.RequireRole(ctx, acl.BankMSPID, acl.RoleLoanOfficer); err == nil {
+		t.Fatal("expected error for caller without loan_officer role, got nil")
+	}
+}
+
+func TestRequireOwner(t *testing.T) {
+	ctx := newCtx("Org1MSP", acl.RoleApplicant, "applicant1", "Jane Doe")
+
+	if _, err := acl.RequireOwner(ctx, "Org1MSP", "applicant1"); err != nil {
+		t.Fatalf("expected original applicant to be authorized, got %v", err)
+	}
+
+	if _, err := acl.RequireOwner(ctx, "Org1MSP", "someoneElse"); err == nil {
+		t.Fatal("expected error for caller who is not the original applicant, got nil")
+	}
+}
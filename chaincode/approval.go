@@ -0,0 +1,272 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+
+	"loan-chaincode/acl"
+)
+
+// approvalKeyObjectType namespaces the composite key under which individual
+// approver votes are stored: approval~<loanID>~<approverID>.
+const approvalKeyObjectType = "approval"
+
+// Large-loan multi-approver thresholds.
+const (
+	largeLoanThreshold     = 100000
+	veryLargeLoanThreshold = 1000000
+)
+
+// ApprovalVote is one approver's recorded decision on a loan, forming part
+// of the audit trail returned alongside loan state by CheckLoanBalance.
+type ApprovalVote struct {
+	ApproverID    string `json:"approverID"`
+	ApproverMSPID string `json:"approverMSPID"`
+	Decision      string `json:"decision"`
+	Timestamp     string `json:"timestamp"`
+	TxID          string `json:"txID"`
+}
+
+// requiredApprovals returns how many distinct matching approver votes a loan
+// of the given amount needs before it transitions to APPROVED or REJECTED.
+func requiredApprovals(loanAmount float64) int {
+	switch {
+	case loanAmount > veryLargeLoanThreshold:
+		return 3
+	case loanAmount > largeLoanThreshold:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// ProposeApproval records the calling loan officer's decision (APPROVED or
+// REJECTED) on a loan. Once enough distinct approvers (per
+// requiredApprovals) have cast the same decision, the loan transitions and,
+// if approved, its repayment schedule is generated.
+func (c *LoanContract) ProposeApproval(ctx contractapi.TransactionContextInterface, loanID string, decision string) error {
+	if decision != "APPROVED" && decision != "REJECTED" {
+		return fmt.Errorf("invalid decision, must be APPROVED or REJECTED")
+	}
+
+	identity, err := acl.RequireRole(ctx, acl.BankMSPID, acl.RoleLoanOfficer)
+	if err != nil {
+		return fmt.Errorf("access denied: %v", err)
+	}
+
+	loanJSON, err := ctx.GetStub().GetState(loanID)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if loanJSON == nil {
+		return fmt.Errorf("loan %s does not exist", loanID)
+	}
+	if isPrivateLoanRecord(loanJSON) {
+		return fmt.Errorf("loan %s was created via ApplyForLoanPrivate and is not compatible with the multi-approver workflow", loanID)
+	}
+
+	var loan Loan
+	if err := json.Unmarshal(loanJSON, &loan); err != nil {
+		return err
+	}
+
+	if loan.Status != "APPLIED" {
+		return fmt.Errorf("loan must be in APPLIED status to propose an approval")
+	}
+
+	vote, err := c.recordVote(ctx, loanID, identity, decision)
+	if err != nil {
+		return err
+	}
+
+	votes, err := c.listApprovals(ctx, loanID)
+	if err != nil {
+		return err
+	}
+
+	// Count the vote just recorded explicitly: reads within a transaction do
+	// not see that transaction's own uncommitted writes, so listApprovals
+	// returns only previously committed votes.
+	matching := 1
+	for _, v := range votes {
+		if v.ApproverID != vote.ApproverID && v.Decision == decision {
+			matching++
+		}
+	}
+	if matching < requiredApprovals(loan.LoanAmount) {
+		// Not enough votes yet; the loan stays APPLIED.
+		return emitEvent(ctx, "ApprovalProposed", loanID, map[string]interface{}{
+			"approverID": vote.ApproverID,
+			"decision":   decision,
+			"votes":      matching,
+			"required":   requiredApprovals(loan.LoanAmount),
+		})
+	}
+
+	if decision == "APPROVED" {
+		// Disburse funds before committing any state change: if the token
+		// transfer fails, this whole transaction is rejected and none of
+		// the votes recorded above are committed either.
+		config, err := c.getConfig(ctx)
+		if err != nil {
+			return err
+		}
+		if err := transferFunds(ctx, config, config.TreasuryAccount, loan.ApplicantEnrollmentID, loan.LoanAmount); err != nil {
+			return fmt.Errorf("failed to disburse loan funds: %v", err)
+		}
+	}
+
+	loan.Status = decision
+
+	updatedLoanJSON, err := json.Marshal(loan)
+	if err != nil {
+		return err
+	}
+	if err := ctx.GetStub().PutState(loanID, updatedLoanJSON); err != nil {
+		return err
+	}
+
+	if decision == "APPROVED" {
+		if _, err := c.generateSchedule(ctx, loan); err != nil {
+			return fmt.Errorf("failed to generate repayment schedule: %v", err)
+		}
+	}
+
+	eventName := "LoanApproved"
+	if decision == "REJECTED" {
+		eventName = "LoanRejected"
+	}
+
+	return emitEvent(ctx, eventName, loanID, map[string]interface{}{
+		"actorMSPID": identity.MSPID,
+		"status":     decision,
+	})
+}
+
+// ListPendingApprovals returns every approver vote recorded so far against a
+// loan that is still awaiting a final decision.
+func (c *LoanContract) ListPendingApprovals(ctx contractapi.TransactionContextInterface, loanID string) ([]ApprovalVote, error) {
+	loanJSON, err := ctx.GetStub().GetState(loanID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if loanJSON == nil {
+		return nil, fmt.Errorf("loan %s does not exist", loanID)
+	}
+	if isPrivateLoanRecord(loanJSON) {
+		return nil, fmt.Errorf("loan %s was created via ApplyForLoanPrivate and is not compatible with the multi-approver workflow", loanID)
+	}
+
+	var loan Loan
+	if err := json.Unmarshal(loanJSON, &loan); err != nil {
+		return nil, err
+	}
+	if loan.Status != "APPLIED" {
+		return nil, fmt.Errorf("loan %s is no longer awaiting approval", loanID)
+	}
+
+	return c.listApprovals(ctx, loanID)
+}
+
+// WithdrawApproval removes the calling loan officer's previously cast vote
+// on a loan that is still awaiting a final decision.
+func (c *LoanContract) WithdrawApproval(ctx contractapi.TransactionContextInterface, loanID string) error {
+	identity, err := acl.RequireRole(ctx, acl.BankMSPID, acl.RoleLoanOfficer)
+	if err != nil {
+		return fmt.Errorf("access denied: %v", err)
+	}
+
+	loanJSON, err := ctx.GetStub().GetState(loanID)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if loanJSON == nil {
+		return fmt.Errorf("loan %s does not exist", loanID)
+	}
+	if isPrivateLoanRecord(loanJSON) {
+		return fmt.Errorf("loan %s was created via ApplyForLoanPrivate and is not compatible with the multi-approver workflow", loanID)
+	}
+
+	var loan Loan
+	if err := json.Unmarshal(loanJSON, &loan); err != nil {
+		return err
+	}
+	if loan.Status != "APPLIED" {
+		return fmt.Errorf("loan %s is no longer awaiting approval", loanID)
+	}
+
+	key, err := c.approvalKey(ctx, loanID, approverID(identity))
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().DelState(key)
+}
+
+func (c *LoanContract) recordVote(ctx contractapi.TransactionContextInterface, loanID string, identity acl.Identity, decision string) (ApprovalVote, error) {
+	txTime, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return ApprovalVote{}, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+
+	txID := ctx.GetStub().GetTxID()
+
+	vote := ApprovalVote{
+		ApproverID:    approverID(identity),
+		ApproverMSPID: identity.MSPID,
+		Decision:      decision,
+		Timestamp:     time.Unix(txTime.Seconds, int64(txTime.Nanos)).UTC().Format(time.RFC3339),
+		TxID:          txID,
+	}
+
+	key, err := c.approvalKey(ctx, loanID, vote.ApproverID)
+	if err != nil {
+		return ApprovalVote{}, err
+	}
+
+	voteJSON, err := json.Marshal(vote)
+	if err != nil {
+		return ApprovalVote{}, err
+	}
+
+	if err := ctx.GetStub().PutState(key, voteJSON); err != nil {
+		return ApprovalVote{}, err
+	}
+
+	return vote, nil
+}
+
+func (c *LoanContract) listApprovals(ctx contractapi.TransactionContextInterface, loanID string) ([]ApprovalVote, error) {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(approvalKeyObjectType, []string{loanID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list approvals: %v", err)
+	}
+	defer iterator.Close()
+
+	var votes []ApprovalVote
+	for iterator.HasNext() {
+		result, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var vote ApprovalVote
+		if err := json.Unmarshal(result.Value, &vote); err != nil {
+			return nil, err
+		}
+		votes = append(votes, vote)
+	}
+
+	return votes, nil
+}
+
+func (c *LoanContract) approvalKey(ctx contractapi.TransactionContextInterface, loanID, approverID string) (string, error) {
+	return ctx.GetStub().CreateCompositeKey(approvalKeyObjectType, []string{loanID, approverID})
+}
+
+func approverID(identity acl.Identity) string {
+	return identity.MSPID + "/" + identity.EnrollmentID
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+
+	"loan-chaincode/acl"
+)
+
+// configKey is the fixed world-state key under which the single Config
+// singleton is stored.
+const configKey = "config"
+
+// Config holds the operator-settable parameters that tie this chaincode to
+// its sibling chaincodes on the channel: a credit-score gate on new
+// applications, and a token chaincode used to actually move funds.
+type Config struct {
+	MinCreditScore  int    `json:"minCreditScore"`
+	CreditScoreCC   string `json:"creditScoreCC"`
+	TokenCC         string `json:"tokenCC"`
+	TreasuryAccount string `json:"treasuryAccount"`
+}
+
+// SetConfig creates or replaces the Config singleton. Only callers with the
+// admin role may call this.
+func (c *LoanContract) SetConfig(ctx contractapi.TransactionContextInterface, minCreditScore int, creditScoreCC, tokenCC, treasuryAccount string) error {
+	if _, err := acl.RequireRole(ctx, acl.BankMSPID, acl.RoleAdmin); err != nil {
+		return fmt.Errorf("access denied: %v", err)
+	}
+
+	if creditScoreCC == "" || tokenCC == "" || treasuryAccount == "" {
+		return fmt.Errorf("creditScoreCC, tokenCC, and treasuryAccount are required")
+	}
+
+	config := Config{
+		MinCreditScore:  minCreditScore,
+		CreditScoreCC:   creditScoreCC,
+		TokenCC:         tokenCC,
+		TreasuryAccount: treasuryAccount,
+	}
+
+	configJSON, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().PutState(configKey, configJSON)
+}
+
+// GetConfig returns the current Config singleton.
+func (c *LoanContract) GetConfig(ctx contractapi.TransactionContextInterface) (*Config, error) {
+	return c.getConfig(ctx)
+}
+
+func (c *LoanContract) getConfig(ctx contractapi.TransactionContextInterface) (*Config, error) {
+	configJSON, err := ctx.GetStub().GetState(configKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config from world state: %v", err)
+	}
+	if configJSON == nil {
+		return nil, fmt.Errorf("config has not been set, call SetConfig first")
+	}
+
+	var config Config
+	if err := json.Unmarshal(configJSON, &config); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
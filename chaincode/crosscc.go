@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// checkCreditScore invokes the sibling credit-score chaincode for applicant
+// and returns an error if the applicant's score is below the configured
+// minimum. It is called at ApplyForLoan time, before any state is written.
+func checkCreditScore(ctx contractapi.TransactionContextInterface, config *Config, applicant string) error {
+	response := ctx.GetStub().InvokeChaincode(config.CreditScoreCC, [][]byte{
+		[]byte("GetScore"),
+		[]byte(applicant),
+	}, ctx.GetStub().GetChannelID())
+
+	if response.Status != 200 {
+		return fmt.Errorf("credit score check failed: %s", response.Message)
+	}
+
+	score, err := strconv.Atoi(string(response.Payload))
+	if err != nil {
+		return fmt.Errorf("failed to parse credit score response: %v", err)
+	}
+
+	if score < config.MinCreditScore {
+		return fmt.Errorf("applicant credit score %d is below the required minimum %d", score, config.MinCreditScore)
+	}
+
+	return nil
+}
+
+// transferFunds invokes the sibling token chaincode to move amount from one
+// account to another. The caller must treat a non-nil error as meaning no
+// funds moved, and must not commit any dependent ledger state in that case
+// so the whole transaction fails atomically.
+func transferFunds(ctx contractapi.TransactionContextInterface, config *Config, from, to string, amount float64) error {
+	response := ctx.GetStub().InvokeChaincode(config.TokenCC, [][]byte{
+		[]byte("Transfer"),
+		[]byte(from),
+		[]byte(to),
+		[]byte(strconv.FormatFloat(amount, 'f', -1, 64)),
+	}, ctx.GetStub().GetChannelID())
+
+	if response.Status != 200 {
+		return fmt.Errorf("token transfer of %.2f from %s to %s failed: %s", amount, from, to, response.Message)
+	}
+
+	return nil
+}
@@ -3,22 +3,43 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"time"
+
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+
+	"loan-chaincode/acl"
+	"loan-chaincode/schedule"
 )
 
+// scheduleKeyObjectType namespaces the composite key under which a loan's
+// repayment schedule is stored, keeping it separate from the main Loan value
+// so the schedule does not bloat reads of the loan itself.
+const scheduleKeyObjectType = "schedule"
+
+// maxConsecutiveLateInstallments is the number of consecutive LATE
+// installments after which a loan is automatically moved to DEFAULTED.
+const maxConsecutiveLateInstallments = 3
+
+// latePenaltyRate is the additional interest (as a fraction of the overdue
+// installment's EMI) accrued once per overdue installment, the first time a
+// repayment is processed after its due date while it remains unpaid.
+const latePenaltyRate = 0.02
+
 type LoanContract struct {
 	contractapi.Contract
 }
 
 type Loan struct {
-	LoanID        string   `json:"loanID"`
-	ApplicantName string   `json:"applicantName"`
-	LoanAmount    float64  `json:"loanAmount"`
-	TermMonths    int      `json:"termMonths"`
-	InterestRate  float64  `json:"interestRate"`
-	Outstanding   float64  `json:"outstanding"`
-	Status        string   `json:"status"`
-	Repayments    []float64 `json:"repayments"`
+	LoanID                string    `json:"loanID"`
+	ApplicantName         string    `json:"applicantName"`
+	LoanAmount            float64   `json:"loanAmount"`
+	TermMonths            int       `json:"termMonths"`
+	InterestRate          float64   `json:"interestRate"`
+	Outstanding           float64   `json:"outstanding"`
+	Status                string    `json:"status"`
+	Repayments            []float64 `json:"repayments"`
+	ApplicantMSPID        string    `json:"applicantMSPID"`
+	ApplicantEnrollmentID string    `json:"applicantEnrollmentID"`
 }
 
 func (c *LoanContract) ApplyForLoan(ctx contractapi.TransactionContextInterface, 
@@ -44,6 +65,16 @@ func (c *LoanContract) ApplyForLoan(ctx contractapi.TransactionContextInterface,
 		return fmt.Errorf("interest rate cannot be negative")
 	}
 
+	// Only the applicant themselves may apply, and their certificate CN must
+	// match the applicantName they're submitting.
+	identity, err := acl.RequireAnyMSPRole(ctx, acl.RoleApplicant)
+	if err != nil {
+		return fmt.Errorf("access denied: %v", err)
+	}
+	if identity.CommonName != applicantName {
+		return fmt.Errorf("applicantName must match the caller's certificate common name")
+	}
+
 	// Check if loan already exists
 	existing, err := ctx.GetStub().GetState(loanID)
 	if err != nil {
@@ -53,16 +84,28 @@ func (c *LoanContract) ApplyForLoan(ctx contractapi.TransactionContextInterface,
 		return fmt.Errorf("loan ID %s already exists", loanID)
 	}
 
+	// Reject applicants below the configured minimum credit score, checked
+	// on-chain via the sibling credit-score chaincode.
+	config, err := c.getConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if err := checkCreditScore(ctx, config, identity.EnrollmentID); err != nil {
+		return err
+	}
+
 	// Create new loan
 	loan := Loan{
-		LoanID:        loanID,
-		ApplicantName: applicantName,
-		LoanAmount:    loanAmount,
-		TermMonths:    termMonths,
-		InterestRate:  interestRate,
-		Outstanding:   loanAmount,
-		Status:        "APPLIED",
-		Repayments:    []float64{},
+		LoanID:                loanID,
+		ApplicantName:         applicantName,
+		LoanAmount:            loanAmount,
+		TermMonths:            termMonths,
+		InterestRate:          interestRate,
+		Outstanding:           loanAmount,
+		Status:                "APPLIED",
+		Repayments:            []float64{},
+		ApplicantMSPID:        identity.MSPID,
+		ApplicantEnrollmentID: identity.EnrollmentID,
 	}
 
 	// Save to ledger
@@ -71,13 +114,26 @@ func (c *LoanContract) ApplyForLoan(ctx contractapi.TransactionContextInterface,
 		return err
 	}
 
-	return ctx.GetStub().PutState(loanID, loanJSON)
+	if err := ctx.GetStub().PutState(loanID, loanJSON); err != nil {
+		return err
+	}
+
+	return emitEvent(ctx, "LoanApplied", loanID, map[string]interface{}{
+		"applicantName": applicantName,
+		"loanAmount":    loanAmount,
+		"termMonths":    termMonths,
+	})
 }
 
-func (c *LoanContract) ApproveLoan(ctx contractapi.TransactionContextInterface, loanID string, status string) error {
-	// Validate status
-	if status != "APPROVED" && status != "REJECTED" {
-		return fmt.Errorf("invalid status, must be APPROVED or REJECTED")
+// ApproveLoan, as a single-signature approval, has been superseded by the
+// multi-approver workflow in approval.go: ProposeApproval, combined with
+// requiredApprovals, decides when a loan actually transitions to
+// APPROVED/REJECTED.
+
+func (c *LoanContract) MakeRepayment(ctx contractapi.TransactionContextInterface, loanID string, repaymentAmount float64) error {
+	// Validate repayment amount
+	if repaymentAmount <= 0 {
+		return fmt.Errorf("repayment amount must be positive")
 	}
 
 	// Get loan from ledger
@@ -88,6 +144,9 @@ func (c *LoanContract) ApproveLoan(ctx contractapi.TransactionContextInterface,
 	if loanJSON == nil {
 		return fmt.Errorf("loan %s does not exist", loanID)
 	}
+	if isPrivateLoanRecord(loanJSON) {
+		return fmt.Errorf("loan %s was created via ApplyForLoanPrivate and is not compatible with the repayment schedule subsystem", loanID)
+	}
 
 	// Unmarshal loan
 	var loan Loan
@@ -96,13 +155,64 @@ func (c *LoanContract) ApproveLoan(ctx contractapi.TransactionContextInterface,
 		return err
 	}
 
-	// Validate current status
-	if loan.Status != "APPLIED" {
-		return fmt.Errorf("loan must be in APPLIED status to be approved/rejected")
+	// Only the original applicant may repay their own loan.
+	if _, err := acl.RequireOwner(ctx, loan.ApplicantMSPID, loan.ApplicantEnrollmentID); err != nil {
+		return fmt.Errorf("access denied: %v", err)
+	}
+
+	// Validate loan status
+	if loan.Status != "APPROVED" {
+		return fmt.Errorf("only APPROVED loans can accept repayments")
+	}
+
+	// Validate repayment doesn't exceed outstanding
+	if repaymentAmount > loan.Outstanding {
+		return fmt.Errorf("repayment amount exceeds outstanding balance")
+	}
+
+	sched, err := c.getSchedule(ctx, loanID)
+	if err != nil {
+		return err
+	}
+
+	txTime, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	now := time.Unix(txTime.Seconds, int64(txTime.Nanos))
+
+	defaulted, err := applyRepaymentToSchedule(sched, repaymentAmount, now)
+	if err != nil {
+		return err
+	}
+
+	if err := c.putSchedule(ctx, loanID, sched); err != nil {
+		return err
+	}
+
+	// Move the repayment from the applicant back to the treasury before
+	// committing any state change, so a failed transfer fails the whole
+	// transaction atomically.
+	config, err := c.getConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if err := transferFunds(ctx, config, loan.ApplicantEnrollmentID, config.TreasuryAccount, repaymentAmount); err != nil {
+		return fmt.Errorf("failed to collect repayment: %v", err)
 	}
 
-	// Update status
-	loan.Status = status
+	// Update loan
+	loan.Outstanding -= repaymentAmount
+	loan.Repayments = append(loan.Repayments, repaymentAmount)
+
+	// Update status if fully paid or defaulted
+	paidOff := false
+	if loan.Outstanding <= 0 {
+		loan.Status = "PAID"
+		paidOff = true
+	} else if defaulted {
+		loan.Status = "DEFAULTED"
+	}
 
 	// Save updated loan
 	updatedLoanJSON, err := json.Marshal(loan)
@@ -110,16 +220,88 @@ func (c *LoanContract) ApproveLoan(ctx contractapi.TransactionContextInterface,
 		return err
 	}
 
-	return ctx.GetStub().PutState(loanID, updatedLoanJSON)
+	if err := ctx.GetStub().PutState(loanID, updatedLoanJSON); err != nil {
+		return err
+	}
+
+	actorMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get client MSP ID: %v", err)
+	}
+
+	if err := emitEvent(ctx, "RepaymentReceived", loanID, map[string]interface{}{
+		"actorMSPID":      actorMSPID,
+		"repaymentAmount": repaymentAmount,
+		"outstanding":     loan.Outstanding,
+	}); err != nil {
+		return err
+	}
+
+	if paidOff {
+		return emitEvent(ctx, "LoanPaidOff", loanID, map[string]interface{}{
+			"actorMSPID": actorMSPID,
+		})
+	}
+
+	if defaulted {
+		return emitEvent(ctx, "LoanDefaulted", loanID, map[string]interface{}{
+			"actorMSPID": actorMSPID,
+		})
+	}
+
+	return nil
 }
 
-func (c *LoanContract) MakeRepayment(ctx contractapi.TransactionContextInterface, loanID string, repaymentAmount float64) error {
-	// Validate repayment amount
-	if repaymentAmount <= 0 {
-		return fmt.Errorf("repayment amount must be positive")
+// GenerateSchedule computes and persists the amortization schedule for a
+// loan that has already been approved. It is normally triggered implicitly
+// once ProposeApproval collects enough votes to approve the loan, but is
+// exposed so a schedule can be regenerated (e.g. after a manual correction)
+// by an operator.
+func (c *LoanContract) GenerateSchedule(ctx contractapi.TransactionContextInterface, loanID string) (*schedule.Schedule, error) {
+	if _, err := acl.RequireRole(ctx, acl.BankMSPID, acl.RoleLoanOfficer); err != nil {
+		return nil, fmt.Errorf("access denied: %v", err)
+	}
+
+	loanJSON, err := ctx.GetStub().GetState(loanID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if loanJSON == nil {
+		return nil, fmt.Errorf("loan %s does not exist", loanID)
+	}
+	if isPrivateLoanRecord(loanJSON) {
+		return nil, fmt.Errorf("loan %s was created via ApplyForLoanPrivate and is not compatible with the repayment schedule subsystem", loanID)
+	}
+
+	var loan Loan
+	if err := json.Unmarshal(loanJSON, &loan); err != nil {
+		return nil, err
+	}
+
+	if loan.Status != "APPROVED" && loan.Status != "DEFAULTED" && loan.Status != "PAID" {
+		return nil, fmt.Errorf("loan must be APPROVED to generate a schedule")
+	}
+
+	return c.generateSchedule(ctx, loan)
+}
+
+// RecordScheduledPayment marks a specific installment as paid (in full or in
+// part) outside of the normal MakeRepayment flow, e.g. when reconciling an
+// off-chain payment against the on-chain schedule.
+func (c *LoanContract) RecordScheduledPayment(ctx contractapi.TransactionContextInterface, loanID string, installmentNo int, amount float64, paidAt string) error {
+	if _, err := acl.RequireRole(ctx, acl.BankMSPID, acl.RoleLoanOfficer); err != nil {
+		return fmt.Errorf("access denied: %v", err)
+	}
+
+	if amount <= 0 {
+		return fmt.Errorf("amount must be positive")
+	}
+
+	parsedPaidAt, err := time.Parse(time.RFC3339, paidAt)
+	if err != nil {
+		return fmt.Errorf("paidAt must be an RFC3339 timestamp: %v", err)
 	}
 
-	// Get loan from ledger
 	loanJSON, err := ctx.GetStub().GetState(loanID)
 	if err != nil {
 		return fmt.Errorf("failed to read from world state: %v", err)
@@ -127,43 +309,194 @@ func (c *LoanContract) MakeRepayment(ctx contractapi.TransactionContextInterface
 	if loanJSON == nil {
 		return fmt.Errorf("loan %s does not exist", loanID)
 	}
+	if isPrivateLoanRecord(loanJSON) {
+		return fmt.Errorf("loan %s was created via ApplyForLoanPrivate and is not compatible with the repayment schedule subsystem", loanID)
+	}
 
-	// Unmarshal loan
-	var loan Loan
-	err = json.Unmarshal(loanJSON, &loan)
+	sched, err := c.getSchedule(ctx, loanID)
 	if err != nil {
 		return err
 	}
 
-	// Validate loan status
-	if loan.Status != "APPROVED" {
-		return fmt.Errorf("only APPROVED loans can accept repayments")
+	found := false
+	for i := range sched.Installments {
+		inst := &sched.Installments[i]
+		if inst.InstallmentNo != installmentNo {
+			continue
+		}
+		found = true
+
+		inst.AmountPaid += amount
+		inst.PaidAt = &parsedPaidAt
+		if inst.AmountPaid >= inst.EMI+inst.PenaltyAccrued {
+			inst.Status = schedule.StatusPaid
+		} else {
+			inst.Status = schedule.StatusPartial
+		}
+		break
+	}
+	if !found {
+		return fmt.Errorf("installment %d not found for loan %s", installmentNo, loanID)
 	}
 
-	// Validate repayment doesn't exceed outstanding
-	if repaymentAmount > loan.Outstanding {
-		return fmt.Errorf("repayment amount exceeds outstanding balance")
+	return c.putSchedule(ctx, loanID, sched)
+}
+
+// GetSchedule returns the persisted amortization schedule for a loan.
+func (c *LoanContract) GetSchedule(ctx contractapi.TransactionContextInterface, loanID string) (*schedule.Schedule, error) {
+	return c.getSchedule(ctx, loanID)
+}
+
+// generateSchedule computes the EMI amortization table for loan using the
+// current transaction's timestamp as the schedule start date (the approval
+// transaction's when called from ProposeApproval, the regenerating
+// transaction's when called from GenerateSchedule), and persists it under
+// the loan's composite schedule key.
+func (c *LoanContract) generateSchedule(ctx contractapi.TransactionContextInterface, loan Loan) (*schedule.Schedule, error) {
+	txTime, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction timestamp: %v", err)
 	}
+	startDate := time.Unix(txTime.Seconds, int64(txTime.Nanos))
 
-	// Update loan
-	loan.Outstanding -= repaymentAmount
-	loan.Repayments = append(loan.Repayments, repaymentAmount)
+	sched := schedule.Generate(loan.LoanID, loan.Outstanding, loan.InterestRate, loan.TermMonths, startDate)
+	if err := c.putSchedule(ctx, loan.LoanID, &sched); err != nil {
+		return nil, err
+	}
 
-	// Update status if fully paid
-	if loan.Outstanding <= 0 {
-		loan.Status = "PAID"
+	return &sched, nil
+}
+
+func (c *LoanContract) scheduleKey(ctx contractapi.TransactionContextInterface, loanID string) (string, error) {
+	return ctx.GetStub().CreateCompositeKey(scheduleKeyObjectType, []string{loanID})
+}
+
+func (c *LoanContract) putSchedule(ctx contractapi.TransactionContextInterface, loanID string, sched *schedule.Schedule) error {
+	key, err := c.scheduleKey(ctx, loanID)
+	if err != nil {
+		return err
 	}
 
-	// Save updated loan
-	updatedLoanJSON, err := json.Marshal(loan)
+	schedJSON, err := json.Marshal(sched)
 	if err != nil {
 		return err
 	}
 
-	return ctx.GetStub().PutState(loanID, updatedLoanJSON)
+	return ctx.GetStub().PutState(key, schedJSON)
 }
 
-func (c *LoanContract) CheckLoanBalance(ctx contractapi.TransactionContextInterface, loanID string) (*Loan, error) {
+func (c *LoanContract) getSchedule(ctx contractapi.TransactionContextInterface, loanID string) (*schedule.Schedule, error) {
+	key, err := c.scheduleKey(ctx, loanID)
+	if err != nil {
+		return nil, err
+	}
+
+	schedJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schedule from world state: %v", err)
+	}
+	if schedJSON == nil {
+		return nil, fmt.Errorf("no repayment schedule found for loan %s", loanID)
+	}
+
+	var sched schedule.Schedule
+	if err := json.Unmarshal(schedJSON, &sched); err != nil {
+		return nil, err
+	}
+
+	return &sched, nil
+}
+
+// isPrivateLoanRecord reports whether the raw ledger bytes stored at a
+// loanID key are a PrivateLoanPublicRecord (created via ApplyForLoanPrivate)
+// rather than a public Loan. The two shapes share no field that would let a
+// naive json.Unmarshal into Loan fail, so callers that only expect the
+// public shape must check this first: unmarshaling a private record into
+// Loan and writing it back would silently drop privateDataHash (breaking
+// VerifyPrivateHash) and zero out TermMonths/InterestRate (producing a
+// degenerate repayment schedule).
+func isPrivateLoanRecord(loanJSON []byte) bool {
+	var probe struct {
+		PrivateDataHash string `json:"privateDataHash"`
+	}
+	if err := json.Unmarshal(loanJSON, &probe); err != nil {
+		return false
+	}
+	return probe.PrivateDataHash != ""
+}
+
+// applyRepaymentToSchedule applies amount against the oldest unpaid
+// installments in order, accruing a late penalty on any installment that is
+// overdue as of now before the payment is applied. It reports whether the
+// loan should transition to DEFAULTED because maxConsecutiveLateInstallments
+// installments are now LATE in a row.
+func applyRepaymentToSchedule(sched *schedule.Schedule, amount float64, now time.Time) (bool, error) {
+	remaining := amount
+
+	for i := range sched.Installments {
+		inst := &sched.Installments[i]
+		if inst.Status == schedule.StatusPaid {
+			continue
+		}
+
+		if !inst.PenaltyApplied && inst.Status != schedule.StatusPaid && now.After(inst.DueDate) && inst.AmountPaid < inst.EMI {
+			inst.PenaltyAccrued += inst.EMI * latePenaltyRate
+			inst.PenaltyApplied = true
+			inst.Status = schedule.StatusLate
+		}
+
+		if remaining <= 0 {
+			continue
+		}
+
+		due := inst.EMI + inst.PenaltyAccrued - inst.AmountPaid
+		if due <= 0 {
+			continue
+		}
+
+		applied := remaining
+		if applied > due {
+			applied = due
+		}
+
+		inst.AmountPaid += applied
+		remaining -= applied
+		inst.PaidAt = &now
+
+		if inst.AmountPaid >= inst.EMI+inst.PenaltyAccrued {
+			inst.Status = schedule.StatusPaid
+		} else if inst.AmountPaid > 0 {
+			inst.Status = schedule.StatusPartial
+		}
+	}
+
+	return consecutiveLateCount(sched) >= maxConsecutiveLateInstallments, nil
+}
+
+func consecutiveLateCount(sched *schedule.Schedule) int {
+	count := 0
+	best := 0
+	for _, inst := range sched.Installments {
+		if inst.Status == schedule.StatusLate {
+			count++
+			if count > best {
+				best = count
+			}
+		} else {
+			count = 0
+		}
+	}
+	return best
+}
+
+// LoanWithApprovals is the response shape for CheckLoanBalance: the loan
+// state plus the full audit trail of approval votes cast against it.
+type LoanWithApprovals struct {
+	Loan
+	Approvals []ApprovalVote `json:"approvals"`
+}
+
+func (c *LoanContract) CheckLoanBalance(ctx contractapi.TransactionContextInterface, loanID string) (*LoanWithApprovals, error) {
 	// Get loan from ledger
 	loanJSON, err := ctx.GetStub().GetState(loanID)
 	if err != nil {
@@ -180,7 +513,31 @@ func (c *LoanContract) CheckLoanBalance(ctx contractapi.TransactionContextInterf
 		return nil, err
 	}
 
-	return &loan, nil
+	approvals, err := c.listApprovals(ctx, loanID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LoanWithApprovals{Loan: loan, Approvals: approvals}, nil
+}
+
+// ChaincodeEvent is the envelope emitted for every loan lifecycle transition.
+// Clients subscribe to these by name (e.g. "LoanApplied") via the event
+// listener subsystem in client/events.
+type ChaincodeEvent struct {
+	LoanID  string                 `json:"loanID"`
+	Payload map[string]interface{} `json:"payload"`
+}
+
+// emitEvent marshals and sets a chaincode event on the transaction context so
+// that subscribed clients are notified once the transaction is committed.
+func emitEvent(ctx contractapi.TransactionContextInterface, name, loanID string, payload map[string]interface{}) error {
+	eventJSON, err := json.Marshal(ChaincodeEvent{LoanID: loanID, Payload: payload})
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().SetEvent(name, eventJSON)
 }
 
 func main() {
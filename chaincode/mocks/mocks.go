@@ -0,0 +1,94 @@
+// Package mocks provides hand-rolled fakes of the fabric-contract-api-go
+// transaction context and client identity, for use in contract unit tests
+// that don't need a full peer or CouchDB instance.
+package mocks
+
+import (
+	"crypto/x509"
+
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// ChaincodeStub is a minimal fake of shim.ChaincodeStubInterface backed by an
+// in-memory map, sufficient for contract tests that only read and write
+// world state.
+type ChaincodeStub struct {
+	shim.ChaincodeStubInterface
+
+	State map[string][]byte
+}
+
+// NewChaincodeStub returns a ChaincodeStub with an empty world state.
+func NewChaincodeStub() *ChaincodeStub {
+	return &ChaincodeStub{State: make(map[string][]byte)}
+}
+
+func (s *ChaincodeStub) GetState(key string) ([]byte, error) {
+	return s.State[key], nil
+}
+
+func (s *ChaincodeStub) PutState(key string, value []byte) error {
+	s.State[key] = value
+	return nil
+}
+
+func (s *ChaincodeStub) DelState(key string) error {
+	delete(s.State, key)
+	return nil
+}
+
+func (s *ChaincodeStub) CreateCompositeKey(objectType string, attributes []string) (string, error) {
+	key := objectType
+	for _, attribute := range attributes {
+		key += "~" + attribute
+	}
+	return key, nil
+}
+
+// ClientIdentity is a fake of cid.ClientIdentity with settable fields so
+// tests can simulate callers with a given MSP, certificate, and attributes.
+type ClientIdentity struct {
+	MSPID      string
+	Cert       *x509.Certificate
+	Attributes map[string]string
+}
+
+func (c *ClientIdentity) GetID() (string, error) {
+	return c.MSPID, nil
+}
+
+func (c *ClientIdentity) GetMSPID() (string, error) {
+	return c.MSPID, nil
+}
+
+func (c *ClientIdentity) GetAttributeValue(attrName string) (string, bool, error) {
+	value, found := c.Attributes[attrName]
+	return value, found, nil
+}
+
+func (c *ClientIdentity) AssertAttributeValue(attrName, attrValue string) error {
+	return nil
+}
+
+func (c *ClientIdentity) GetX509Certificate() (*x509.Certificate, error) {
+	return c.Cert, nil
+}
+
+// TransactionContext is a fake of contractapi.TransactionContextInterface
+// that returns a preconfigured stub and client identity.
+type TransactionContext struct {
+	contractapi.TransactionContext
+
+	Stub           *ChaincodeStub
+	ClientIdentity *ClientIdentity
+}
+
+func (t *TransactionContext) GetStub() shim.ChaincodeStubInterface {
+	return t.Stub
+}
+
+func (t *TransactionContext) GetClientIdentity() cid.ClientIdentity {
+	return t.ClientIdentity
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+
+	"loan-chaincode/acl"
+)
+
+// privateDetailsCollection is the private data collection (defined in
+// collections_config.json) that holds PII for loans applied for via
+// ApplyForLoanPrivate. Only the applicant's org and the bank org are
+// members, so the data is never replicated to, or readable by, any other
+// organization on the channel.
+const privateDetailsCollection = "loanPrivateDetails"
+
+// transientDetailsKey is the key under which callers must place the
+// PII payload in the transaction's transient map, so it never appears in
+// the (world-readable, endorsed) transaction proposal or in block data.
+const transientDetailsKey = "privateDetails"
+
+// PrivateLoanDetails holds the PII collected for a loan application. It is
+// only ever written to the loanPrivateDetails private data collection, never
+// to the public ledger.
+type PrivateLoanDetails struct {
+	LoanID      string `json:"loanID"`
+	LegalName   string `json:"legalName"`
+	NationalID  string `json:"nationalID"`
+	Address     string `json:"address"`
+	IncomeProof string `json:"incomeProof"`
+}
+
+// PrivateLoanPublicRecord is the subset of loan data that is safe to hold on
+// the public ledger when the underlying PII lives in a private data
+// collection: non-sensitive loan terms plus a hash that proves the private
+// payload's integrity without revealing it.
+type PrivateLoanPublicRecord struct {
+	LoanID                string  `json:"loanID"`
+	LoanAmount            float64 `json:"loanAmount"`
+	Status                string  `json:"status"`
+	Outstanding           float64 `json:"outstanding"`
+	PrivateDataHash       string  `json:"privateDataHash"`
+	ApplicantMSPID        string  `json:"applicantMSPID"`
+	ApplicantEnrollmentID string  `json:"applicantEnrollmentID"`
+}
+
+// ApplyForLoanPrivate applies for a loan whose PII is kept off the public
+// ledger. The caller must pass the PII via the transaction's transient map
+// under transientDetailsKey, JSON-encoded as a PrivateLoanDetails (minus
+// LoanID, which is taken from the loanID argument).
+func (c *LoanContract) ApplyForLoanPrivate(ctx contractapi.TransactionContextInterface, loanID string, loanAmount float64, termMonths int, interestRate float64) error {
+	if loanID == "" {
+		return fmt.Errorf("loan ID cannot be empty")
+	}
+	if loanAmount <= 0 {
+		return fmt.Errorf("loan amount must be positive")
+	}
+
+	// Only the applicant themselves may apply, and their certificate CN must
+	// match the legal name they're submitting as private KYC data.
+	identity, err := acl.RequireAnyMSPRole(ctx, acl.RoleApplicant)
+	if err != nil {
+		return fmt.Errorf("access denied: %v", err)
+	}
+
+	existing, err := ctx.GetStub().GetState(loanID)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if existing != nil {
+		return fmt.Errorf("loan ID %s already exists", loanID)
+	}
+
+	transientMap, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return fmt.Errorf("failed to get transient data: %v", err)
+	}
+
+	detailsJSON, ok := transientMap[transientDetailsKey]
+	if !ok {
+		return fmt.Errorf("%s must be provided in the transient map", transientDetailsKey)
+	}
+
+	var details PrivateLoanDetails
+	if err := json.Unmarshal(detailsJSON, &details); err != nil {
+		return fmt.Errorf("failed to parse private loan details: %v", err)
+	}
+	details.LoanID = loanID
+
+	if details.LegalName != identity.CommonName {
+		return fmt.Errorf("legalName must match the caller's certificate common name")
+	}
+
+	// Reject applicants below the configured minimum credit score, same as
+	// the public ApplyForLoan path.
+	config, err := c.getConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if err := checkCreditScore(ctx, config, identity.EnrollmentID); err != nil {
+		return err
+	}
+
+	normalizedDetailsJSON, err := json.Marshal(details)
+	if err != nil {
+		return err
+	}
+
+	if err := ctx.GetStub().PutPrivateData(privateDetailsCollection, loanID, normalizedDetailsJSON); err != nil {
+		return fmt.Errorf("failed to write private loan details: %v", err)
+	}
+
+	record := PrivateLoanPublicRecord{
+		LoanID:                loanID,
+		LoanAmount:            loanAmount,
+		Status:                "APPLIED",
+		Outstanding:           loanAmount,
+		PrivateDataHash:       hashPrivateDetails(normalizedDetailsJSON),
+		ApplicantMSPID:        identity.MSPID,
+		ApplicantEnrollmentID: identity.EnrollmentID,
+	}
+
+	recordJSON, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	if err := ctx.GetStub().PutState(loanID, recordJSON); err != nil {
+		return err
+	}
+
+	return emitEvent(ctx, "LoanApplied", loanID, map[string]interface{}{
+		"loanAmount": loanAmount,
+		"termMonths": termMonths,
+		"private":    true,
+	})
+}
+
+// GetPrivateLoanDetails returns the PII for a loan applied for via
+// ApplyForLoanPrivate. This function makes no membership check of its own:
+// Fabric only delivers private data to peers of organizations that are
+// members of privateDetailsCollection, and because the collection sets
+// memberOnlyRead, a peer rejects the read with an error when the client is
+// not from a member organization. A non-member peer has no data to return.
+func (c *LoanContract) GetPrivateLoanDetails(ctx contractapi.TransactionContextInterface, loanID string) (*PrivateLoanDetails, error) {
+	detailsJSON, err := ctx.GetStub().GetPrivateData(privateDetailsCollection, loanID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read private loan details: %v", err)
+	}
+	if detailsJSON == nil {
+		return nil, fmt.Errorf("no private details found for loan %s, or caller's org is not a collection member", loanID)
+	}
+
+	var details PrivateLoanDetails
+	if err := json.Unmarshal(detailsJSON, &details); err != nil {
+		return nil, err
+	}
+
+	return &details, nil
+}
+
+// VerifyPrivateHash recomputes the hash of the currently stored private
+// details for loanID and compares it against the hash recorded on the
+// public ledger, proving (without revealing the PII to the caller of this
+// function) that the private data has not been tampered with.
+func (c *LoanContract) VerifyPrivateHash(ctx contractapi.TransactionContextInterface, loanID string) (bool, error) {
+	recordJSON, err := ctx.GetStub().GetState(loanID)
+	if err != nil {
+		return false, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if recordJSON == nil {
+		return false, fmt.Errorf("loan %s does not exist", loanID)
+	}
+
+	var record PrivateLoanPublicRecord
+	if err := json.Unmarshal(recordJSON, &record); err != nil {
+		return false, err
+	}
+
+	detailsJSON, err := ctx.GetStub().GetPrivateData(privateDetailsCollection, loanID)
+	if err != nil {
+		return false, fmt.Errorf("failed to read private loan details: %v", err)
+	}
+	if detailsJSON == nil {
+		return false, fmt.Errorf("no private details found for loan %s, or caller's org is not a collection member", loanID)
+	}
+
+	// Re-marshal through the same struct so field ordering can't cause a
+	// spurious mismatch.
+	var details PrivateLoanDetails
+	if err := json.Unmarshal(detailsJSON, &details); err != nil {
+		return false, err
+	}
+	normalizedDetailsJSON, err := json.Marshal(details)
+	if err != nil {
+		return false, err
+	}
+
+	return hashPrivateDetails(normalizedDetailsJSON) == record.PrivateDataHash, nil
+}
+
+func hashPrivateDetails(detailsJSON []byte) string {
+	sum := sha256.Sum256(detailsJSON)
+	return hex.EncodeToString(sum[:])
+}
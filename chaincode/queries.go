@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// PaginatedQueryResult wraps a page of loans returned from a rich query,
+// mirroring the bookmark/fetchedRecordsCount shape CouchDB pagination uses.
+type PaginatedQueryResult struct {
+	Records             []*Loan `json:"records"`
+	FetchedRecordsCount int32   `json:"fetchedRecordsCount"`
+	Bookmark            string  `json:"bookmark"`
+}
+
+// HistoryQueryResult is a single entry in a key's modification history.
+type HistoryQueryResult struct {
+	TxID      string `json:"txId"`
+	Timestamp string `json:"timestamp"`
+	Record    *Loan  `json:"record"`
+	IsDelete  bool   `json:"isDelete"`
+}
+
+// QueryLoans runs an arbitrary CouchDB Mango selector against the loan
+// collection with pagination, returning a page of results plus a bookmark
+// that can be passed back in to fetch the next page.
+func (c *LoanContract) QueryLoans(ctx contractapi.TransactionContextInterface, selector string, pageSize int32, bookmark string) (*PaginatedQueryResult, error) {
+	if pageSize <= 0 {
+		return nil, fmt.Errorf("pageSize must be positive")
+	}
+
+	resultsIterator, metadata, err := ctx.GetStub().GetQueryResultWithPagination(selector, pageSize, bookmark)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute rich query: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	loans, err := collectLoans(resultsIterator)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PaginatedQueryResult{
+		Records:             loans,
+		FetchedRecordsCount: metadata.FetchedRecordsCount,
+		Bookmark:            metadata.Bookmark,
+	}, nil
+}
+
+// QueryByStatus returns a page of loans whose Status field matches status.
+func (c *LoanContract) QueryByStatus(ctx contractapi.TransactionContextInterface, status string, pageSize int32, bookmark string) (*PaginatedQueryResult, error) {
+	selector, err := json.Marshal(map[string]interface{}{
+		"selector": map[string]interface{}{
+			"status": status,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return c.QueryLoans(ctx, string(selector), pageSize, bookmark)
+}
+
+// QueryByApplicant returns a page of loans applied for by applicantName.
+func (c *LoanContract) QueryByApplicant(ctx contractapi.TransactionContextInterface, applicantName string, pageSize int32, bookmark string) (*PaginatedQueryResult, error) {
+	selector, err := json.Marshal(map[string]interface{}{
+		"selector": map[string]interface{}{
+			"applicantName": applicantName,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return c.QueryLoans(ctx, string(selector), pageSize, bookmark)
+}
+
+// QueryByAmountRange returns a page of loans whose LoanAmount falls between
+// minAmount and maxAmount, inclusive.
+func (c *LoanContract) QueryByAmountRange(ctx contractapi.TransactionContextInterface, minAmount, maxAmount float64, pageSize int32, bookmark string) (*PaginatedQueryResult, error) {
+	if minAmount > maxAmount {
+		return nil, fmt.Errorf("minAmount cannot exceed maxAmount")
+	}
+
+	selector, err := json.Marshal(map[string]interface{}{
+		"selector": map[string]interface{}{
+			"loanAmount": map[string]interface{}{
+				"$gte": minAmount,
+				"$lte": maxAmount,
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return c.QueryLoans(ctx, string(selector), pageSize, bookmark)
+}
+
+// GetLoanHistory returns the full modification history of a loan, as
+// recorded by the blockchain ledger, in the order the ledger's history
+// iterator yields it; the entries are not re-sorted.
+func (c *LoanContract) GetLoanHistory(ctx contractapi.TransactionContextInterface, loanID string) ([]HistoryQueryResult, error) {
+	resultsIterator, err := ctx.GetStub().GetHistoryForKey(loanID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get history for loan %s: %v", loanID, err)
+	}
+	defer resultsIterator.Close()
+
+	var history []HistoryQueryResult
+	for resultsIterator.HasNext() {
+		modification, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		entry := HistoryQueryResult{
+			TxID:      modification.TxId,
+			Timestamp: modification.Timestamp.AsTime().UTC().Format("2006-01-02T15:04:05Z"),
+			IsDelete:  modification.IsDelete,
+		}
+
+		if !modification.IsDelete && modification.Value != nil {
+			var loan Loan
+			if err := json.Unmarshal(modification.Value, &loan); err != nil {
+				return nil, err
+			}
+			entry.Record = &loan
+		}
+
+		history = append(history, entry)
+	}
+
+	return history, nil
+}
+
+// collectLoans drains a state query iterator into a slice of Loan records,
+// skipping entries whose value fails to unmarshal into a Loan. Other JSON
+// objects matched by an overly broad selector (e.g. schedule or approval
+// records) unmarshal without error and are returned as Loan records with
+// only the shared fields set.
+func collectLoans(resultsIterator shim.StateQueryIteratorInterface) ([]*Loan, error) {
+	var loans []*Loan
+	for resultsIterator.HasNext() {
+		queryResult, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var loan Loan
+		if err := json.Unmarshal(queryResult.Value, &loan); err != nil {
+			continue
+		}
+		loans = append(loans, &loan)
+	}
+
+	return loans, nil
+}
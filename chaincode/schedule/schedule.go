@@ -0,0 +1,106 @@
+// Package schedule computes and represents amortized (EMI) repayment
+// schedules for loans, independent of how the schedule is persisted on the
+// ledger.
+package schedule
+
+import (
+	"math"
+	"time"
+)
+
+// Installment status values.
+const (
+	StatusDue     = "DUE"
+	StatusPaid    = "PAID"
+	StatusLate    = "LATE"
+	StatusPartial = "PARTIAL"
+)
+
+// Installment is a single entry in an amortization table: it splits the
+// fixed EMI amount into principal and interest components and tracks how
+// much of it has actually been paid.
+type Installment struct {
+	InstallmentNo  int        `json:"installmentNo"`
+	DueDate        time.Time  `json:"dueDate"`
+	EMI            float64    `json:"emi"`
+	Principal      float64    `json:"principal"`
+	Interest       float64    `json:"interest"`
+	PenaltyAccrued float64    `json:"penaltyAccrued"`
+	AmountPaid     float64    `json:"amountPaid"`
+	PaidAt         *time.Time `json:"paidAt,omitempty"`
+	Status         string     `json:"status"`
+
+	// PenaltyApplied records whether the late penalty for this installment's
+	// current overdue period has already been accrued, so repeated partial
+	// repayments against it don't compound the penalty.
+	PenaltyApplied bool `json:"penaltyApplied"`
+}
+
+// Schedule is the full amortization table for a loan.
+type Schedule struct {
+	LoanID       string        `json:"loanID"`
+	EMI          float64       `json:"emi"`
+	Installments []Installment `json:"installments"`
+}
+
+// MonthlyRate converts an annual percentage rate into the monthly decimal
+// rate used by the EMI formula.
+func MonthlyRate(annualRatePct float64) float64 {
+	return annualRatePct / 12 / 100
+}
+
+// ComputeEMI returns the fixed monthly installment amount for a principal,
+// monthly interest rate, and term in months, using the standard amortization
+// formula EMI = P * r * (1+r)^n / ((1+r)^n - 1). A zero rate degenerates to
+// an equal split of principal across the term.
+func ComputeEMI(principal, monthlyRate float64, termMonths int) float64 {
+	if termMonths <= 0 {
+		return 0
+	}
+	if monthlyRate == 0 {
+		return principal / float64(termMonths)
+	}
+
+	factor := math.Pow(1+monthlyRate, float64(termMonths))
+	return principal * monthlyRate * factor / (factor - 1)
+}
+
+// Generate builds the full amortization table for a loan starting from
+// startDate, splitting each EMI into principal and interest based on the
+// outstanding balance at the time of that installment.
+func Generate(loanID string, principal, annualRatePct float64, termMonths int, startDate time.Time) Schedule {
+	monthlyRate := MonthlyRate(annualRatePct)
+	emi := ComputeEMI(principal, monthlyRate, termMonths)
+
+	installments := make([]Installment, 0, termMonths)
+	remaining := principal
+	for i := 1; i <= termMonths; i++ {
+		interest := remaining * monthlyRate
+		principalComponent := emi - interest
+		if i == termMonths {
+			// Absorb any rounding drift into the final installment.
+			principalComponent = remaining
+			emi = principalComponent + interest
+		}
+		remaining -= principalComponent
+
+		installments = append(installments, Installment{
+			InstallmentNo: i,
+			DueDate:       startDate.AddDate(0, i, 0),
+			EMI:           round2(emi),
+			Principal:     round2(principalComponent),
+			Interest:      round2(interest),
+			Status:        StatusDue,
+		})
+	}
+
+	return Schedule{
+		LoanID:       loanID,
+		EMI:          round2(ComputeEMI(principal, monthlyRate, termMonths)),
+		Installments: installments,
+	}
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
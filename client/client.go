@@ -7,8 +7,11 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/providers/fab"
 	"github.com/hyperledger/fabric-sdk-go/pkg/core/config"
 	"github.com/hyperledger/fabric-sdk-go/pkg/gateway"
+
+	"loan-client/events"
 )
 
 type Loan struct {
@@ -22,7 +25,24 @@ type Loan struct {
 	Repayments    []float64 `json:"repayments"`
 }
 
-func populateWallet(wallet *gateway.Wallet) error {
+// PaginatedQueryResult mirrors the chaincode's rich-query response shape.
+type PaginatedQueryResult struct {
+	Records             []*Loan `json:"records"`
+	FetchedRecordsCount int32   `json:"fetchedRecordsCount"`
+	Bookmark            string  `json:"bookmark"`
+}
+
+// HistoryQueryResult mirrors a single entry of the chaincode's loan history.
+type HistoryQueryResult struct {
+	TxID      string `json:"txId"`
+	Timestamp string `json:"timestamp"`
+	Record    *Loan  `json:"record"`
+	IsDelete  bool   `json:"isDelete"`
+}
+
+// populateWalletIdentity loads the MSP material for a test-network user
+// (e.g. "User1@org1.example.com") and stores it in wallet under label.
+func populateWalletIdentity(wallet *gateway.Wallet, label, mspID, user string) error {
 	credPath := filepath.Join(
 		"..",
 		"..",
@@ -31,7 +51,7 @@ func populateWallet(wallet *gateway.Wallet) error {
 		"peerOrganizations",
 		"org1.example.com",
 		"users",
-		"User1@org1.example.com",
+		user,
 		"msp",
 	)
 
@@ -56,8 +76,8 @@ func populateWallet(wallet *gateway.Wallet) error {
 		return err
 	}
 
-	identity := gateway.NewX509Identity("Org1MSP", string(cert), string(key))
-	return wallet.Put("appUser", identity)
+	identity := gateway.NewX509Identity(mspID, string(cert), string(key))
+	return wallet.Put(label, identity)
 }
 
 func main() {
@@ -74,10 +94,17 @@ func main() {
 		log.Fatalf("Failed to create wallet: %v", err)
 	}
 
-	if !wallet.Exists("appUser") {
-		err = populateWallet(wallet)
-		if err != nil {
-			log.Fatalf("Failed to populate wallet: %v", err)
+	// The applicant and loan officer are distinct identities so that
+	// ACL-gated transactions (ApplyForLoan vs ProposeApproval) are submitted
+	// by the caller the chaincode actually expects.
+	if !wallet.Exists("applicant") {
+		if err := populateWalletIdentity(wallet, "applicant", "Org1MSP", "User1@org1.example.com"); err != nil {
+			log.Fatalf("Failed to populate applicant identity: %v", err)
+		}
+	}
+	if !wallet.Exists("loanOfficer") {
+		if err := populateWalletIdentity(wallet, "loanOfficer", "BankMSP", "User2@org1.example.com"); err != nil {
+			log.Fatalf("Failed to populate loan officer identity: %v", err)
 		}
 	}
 
@@ -91,30 +118,59 @@ func main() {
 		"connection-org1.yaml",
 	)
 
-	gw, err := gateway.Connect(
+	applicantGW, err := gateway.Connect(
+		gateway.WithConfig(config.FromFile(filepath.Clean(ccpPath))),
+		gateway.WithIdentity(wallet, "applicant"),
+	)
+	if err != nil {
+		log.Fatalf("Failed to connect to gateway as applicant: %v", err)
+	}
+	defer applicantGW.Close()
+
+	loanOfficerGW, err := gateway.Connect(
 		gateway.WithConfig(config.FromFile(filepath.Clean(ccpPath))),
-		gateway.WithIdentity(wallet, "appUser"),
+		gateway.WithIdentity(wallet, "loanOfficer"),
 	)
 	if err != nil {
-		log.Fatalf("Failed to connect to gateway: %v", err)
+		log.Fatalf("Failed to connect to gateway as loan officer: %v", err)
 	}
-	defer gw.Close()
+	defer loanOfficerGW.Close()
 
-	network, err := gw.GetNetwork("mychannel")
+	network, err := applicantGW.GetNetwork("mychannel")
 	if err != nil {
 		log.Fatalf("Failed to get network: %v", err)
 	}
 
 	contract := network.GetContract("loancontract")
 
-	// Test Case 1: Apply for a loan
+	officerNetwork, err := loanOfficerGW.GetNetwork("mychannel")
+	if err != nil {
+		log.Fatalf("Failed to get network as loan officer: %v", err)
+	}
+	officerContract := officerNetwork.GetContract("loancontract")
+
+	// Test Case 0: Configure the sibling credit-score and token chaincodes.
+	// In this demo the loan officer identity also carries the admin role.
+	log.Println("--> Test Case 0: Configure credit-score and token chaincode integration")
+	_, err = officerContract.SubmitTransaction(
+		"SetConfig",
+		"650",
+		"creditscore",
+		"token",
+		"treasury",
+	)
+	if err != nil {
+		log.Fatalf("Failed to set config: %v", err)
+	}
+
+	// Test Case 1: Apply for a loan as the applicant
 	log.Println("--> Test Case 1: Apply for a loan")
 	_, err = contract.SubmitTransaction(
-		"ApplyForLoan", 
-		"loan1", 
-		"John Doe", 
-		"5000", 
-		"12", 
+		"ApplyForLoan",
+		"loan1",
+		"John Doe",
+		"5000",
+		"12",
 		"5.5",
 	)
 	if err != nil {
@@ -122,6 +178,17 @@ func main() {
 	}
 	log.Println("Loan successfully applied")
 
+	// Test Case 1b: Approve the loan as the loan officer. Loans at this
+	// amount only require a single approver; larger loans require
+	// ProposeApproval to be submitted by multiple distinct loan officers
+	// before the loan transitions out of APPLIED.
+	log.Println("--> Test Case 1b: Approve the loan as the loan officer")
+	_, err = officerContract.SubmitTransaction("ProposeApproval", "loan1", "APPROVED")
+	if err != nil {
+		log.Fatalf("Failed to approve loan: %v", err)
+	}
+	log.Println("Loan successfully approved")
+
 	// Test Case 2: Check loan status
 	log.Println("--> Test Case 2: Check loan status")
 	result, err := contract.EvaluateTransaction("CheckLoanBalance", "loan1")
@@ -136,5 +203,70 @@ func main() {
 	log.Printf("Loan Status: %s\n", loan.Status)
 	log.Printf("Outstanding Balance: %.2f\n", loan.Outstanding)
 
+	// Test Case 3: Listen for chaincode events
+	log.Println("--> Test Case 3: Listen for loan lifecycle events")
+	listener := events.NewListener(network, contract, filepath.Join(walletPath, "checkpoint.json"))
+	listener.OnEvent("LoanApplied", func(event *fab.CCEvent) {
+		log.Printf("Received event %s: %s\n", event.EventName, string(event.Payload))
+	})
+
+	stop := make(chan struct{})
+	go func() {
+		if err := listener.SubscribeChaincodeEvents("LoanApplied", stop); err != nil {
+			log.Printf("event subscription ended: %v", err)
+		}
+	}()
+	defer close(stop)
+
+	// Test Case 4: CouchDB-backed rich queries
+	log.Println("--> Test Case 4: Query loans by status")
+	queryResult, err := contract.EvaluateTransaction("QueryByStatus", "APPLIED", "10", "")
+	if err != nil {
+		log.Fatalf("Failed to query loans by status: %v", err)
+	}
+	var page PaginatedQueryResult
+	err = json.Unmarshal(queryResult, &page)
+	if err != nil {
+		log.Fatalf("Failed to parse query result: %v", err)
+	}
+	log.Printf("Fetched %d loan(s), bookmark: %s\n", page.FetchedRecordsCount, page.Bookmark)
+
+	log.Println("--> Test Case 5: Fetch loan history")
+	historyResult, err := contract.EvaluateTransaction("GetLoanHistory", "loan1")
+	if err != nil {
+		log.Fatalf("Failed to fetch loan history: %v", err)
+	}
+	var history []HistoryQueryResult
+	err = json.Unmarshal(historyResult, &history)
+	if err != nil {
+		log.Fatalf("Failed to parse loan history: %v", err)
+	}
+	log.Printf("Loan history has %d entr(y/ies)\n", len(history))
+
+	// Test Case 6: Apply for a loan with PII kept in a private data
+	// collection, passed via the transaction's transient map.
+	log.Println("--> Test Case 6: Apply for a loan with private KYC details")
+	privateDetails, err := json.Marshal(map[string]string{
+		"legalName":   "John Doe",
+		"nationalID":  "ABCD1234E",
+		"address":     "221B Baker Street",
+		"incomeProof": "payslip-2026-06.pdf",
+	})
+	if err != nil {
+		log.Fatalf("Failed to encode private loan details: %v", err)
+	}
+
+	transaction, err := contract.CreateTransaction(
+		"ApplyForLoanPrivate",
+		gateway.WithTransient(map[string][]byte{"privateDetails": privateDetails}),
+	)
+	if err != nil {
+		log.Fatalf("Failed to create private transaction: %v", err)
+	}
+	if _, err := transaction.Submit("loan2", "8000", "24", "6.0"); err != nil {
+		log.Fatalf("Failed to apply for private loan: %v", err)
+	}
+	log.Println("Private loan successfully applied")
+
 	log.Println("============ Client completed successfully ============")
 }
\ No newline at end of file
@@ -0,0 +1,178 @@
+// Package events provides a client-side listener subsystem for Fabric
+// chaincode events, block events, and filtered block events. It persists a
+// checkpoint of the last processed block for a consuming service (e.g. an
+// accounting service). Only SubscribeBlockEvents reads the checkpoint back,
+// to skip blocks already processed before a restart; events emitted while
+// the listener was not running are not replayed.
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/providers/fab"
+	"github.com/hyperledger/fabric-sdk-go/pkg/gateway"
+)
+
+// Handler is invoked for every chaincode event that matches a subscription.
+// Handlers are called synchronously, in the order events are received.
+type Handler func(event *fab.CCEvent)
+
+// BlockHandler is invoked for every full block event.
+type BlockHandler func(event *fab.BlockEvent)
+
+// FilteredBlockHandler is invoked for every filtered block event.
+type FilteredBlockHandler func(event *fab.FilteredBlockEvent)
+
+// Checkpoint tracks the last block number this listener has fully processed.
+type Checkpoint struct {
+	LastBlock uint64 `json:"lastBlock"`
+}
+
+// Listener subscribes to chaincode, block, and filtered block events on a
+// channel and dispatches them to registered handlers, persisting a
+// checkpoint to disk after each block is processed.
+type Listener struct {
+	network        *gateway.Network
+	contract       *gateway.Contract
+	checkpointPath string
+
+	mu       sync.Mutex
+	handlers map[string][]Handler
+}
+
+// NewListener creates a Listener bound to the given network and contract.
+// checkpointPath is where the last-processed-block checkpoint is persisted.
+func NewListener(network *gateway.Network, contract *gateway.Contract, checkpointPath string) *Listener {
+	return &Listener{
+		network:        network,
+		contract:       contract,
+		checkpointPath: checkpointPath,
+		handlers:       make(map[string][]Handler),
+	}
+}
+
+// OnEvent registers a handler for chaincode events matching eventName.
+// eventName may be a regular expression, per the Fabric event filter syntax.
+func (l *Listener) OnEvent(eventName string, handler Handler) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.handlers[eventName] = append(l.handlers[eventName], handler)
+}
+
+// SubscribeChaincodeEvents registers a chaincode event filter and dispatches
+// matching events to any handler registered for eventFilter via OnEvent. It
+// blocks until the stop channel is closed.
+func (l *Listener) SubscribeChaincodeEvents(eventFilter string, stop <-chan struct{}) error {
+	reg, notifier, err := l.contract.RegisterEvent(eventFilter)
+	if err != nil {
+		return fmt.Errorf("failed to register chaincode event listener: %v", err)
+	}
+	defer l.contract.Unregister(reg)
+
+	for {
+		select {
+		case event := <-notifier:
+			l.dispatch(eventFilter, event)
+			if err := l.checkpointBlock(event.BlockNumber); err != nil {
+				log.Printf("failed to persist checkpoint for block %d: %v", event.BlockNumber, err)
+			}
+		case <-stop:
+			return nil
+		}
+	}
+}
+
+// SubscribeBlockEvents registers for full block events and dispatches each
+// block to handler. The legacy gateway API has no server-side option to
+// resume delivery from a given block height, so resume is implemented
+// client-side: blocks at or below the last persisted checkpoint (if any)
+// are silently skipped rather than re-delivered to handler. It blocks until
+// the stop channel is closed.
+func (l *Listener) SubscribeBlockEvents(handler BlockHandler, stop <-chan struct{}) error {
+	cp, hasCheckpoint := l.loadCheckpoint()
+
+	reg, notifier, err := l.network.RegisterBlockEvent()
+	if err != nil {
+		return fmt.Errorf("failed to register block event listener: %v", err)
+	}
+	defer l.network.Unregister(reg)
+
+	for {
+		select {
+		case event := <-notifier:
+			blockNumber := event.Block.Header.Number
+			if hasCheckpoint && blockNumber <= cp.LastBlock {
+				continue
+			}
+			handler(event)
+			if err := l.checkpointBlock(blockNumber); err != nil {
+				log.Printf("failed to persist checkpoint for block %d: %v", blockNumber, err)
+			}
+		case <-stop:
+			return nil
+		}
+	}
+}
+
+// SubscribeFilteredBlockEvents registers for filtered block events (a
+// lighter-weight summary of each block) and dispatches each to handler. It
+// blocks until the stop channel is closed.
+func (l *Listener) SubscribeFilteredBlockEvents(handler FilteredBlockHandler, stop <-chan struct{}) error {
+	reg, notifier, err := l.network.RegisterFilteredBlockEvent()
+	if err != nil {
+		return fmt.Errorf("failed to register filtered block event listener: %v", err)
+	}
+	defer l.network.Unregister(reg)
+
+	for {
+		select {
+		case event := <-notifier:
+			handler(event)
+			if event.FilteredBlock != nil {
+				if err := l.checkpointBlock(event.FilteredBlock.Number); err != nil {
+					log.Printf("failed to persist checkpoint for block %d: %v", event.FilteredBlock.Number, err)
+				}
+			}
+		case <-stop:
+			return nil
+		}
+	}
+}
+
+func (l *Listener) dispatch(eventFilter string, event *fab.CCEvent) {
+	l.mu.Lock()
+	handlers := append([]Handler(nil), l.handlers[eventFilter]...)
+	l.mu.Unlock()
+
+	for _, handler := range handlers {
+		handler(event)
+	}
+}
+
+func (l *Listener) loadCheckpoint() (Checkpoint, bool) {
+	data, err := os.ReadFile(l.checkpointPath)
+	if err != nil {
+		return Checkpoint{}, false
+	}
+
+	var cp Checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return Checkpoint{}, false
+	}
+
+	return cp, true
+}
+
+func (l *Listener) checkpointBlock(blockNumber uint64) error {
+	cp := Checkpoint{LastBlock: blockNumber}
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(l.checkpointPath, data, 0o644)
+}